@@ -0,0 +1,317 @@
+package main
+
+// BitBoard is a flat, allocation-free stand-in for board during the
+// elimination and substitution passes (see main.go and dlx.go): 81
+// cells, each a uint16 with the value in the low 4 bits and the 9-bit
+// candidate mask in the next 9 (bits 4-12); cell keeps the same two
+// fields but with a 5-bit value, so its mask sits at bits 5-13. unitTable
+// is precomputed once so checkCellBB never has to build a tuple. Because
+// it's a plain array, not an array of pointers like board, assigning one
+// BitBoard to another (`b2 := b1`) copies the whole board in a single
+// array copy -- no helper needed.
+type BitBoard [81]uint16
+
+// unitTable[i][0/1/2] holds the row/column/box that cell i belongs to,
+// 9 cells each, with i itself always first, so the unit helpers below
+// can treat u[0] as the cell being checked.
+var unitTable [81][3][9]uint8
+
+func init() {
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			i := r*9 + c
+
+			row := [9]uint8{uint8(i)}
+			k := 1
+			for cc := 0; cc < 9; cc++ {
+				if cc != c {
+					row[k] = uint8(r*9 + cc)
+					k++
+				}
+			}
+			unitTable[i][0] = row
+
+			col := [9]uint8{uint8(i)}
+			k = 1
+			for rr := 0; rr < 9; rr++ {
+				if rr != r {
+					col[k] = uint8(rr*9 + c)
+					k++
+				}
+			}
+			unitTable[i][1] = col
+
+			box := [9]uint8{uint8(i)}
+			k = 1
+			br, bc := (r/3)*3, (c/3)*3
+			for dr := 0; dr < 3; dr++ {
+				for dc := 0; dc < 3; dc++ {
+					rr, cc := br+dr, bc+dc
+					if rr == r && cc == c {
+						continue
+					}
+					box[k] = uint8(rr*9 + cc)
+					k++
+				}
+			}
+			unitTable[i][2] = box
+		}
+	}
+}
+
+func bbValue(bb *BitBoard, i int) int {
+	return int(bb[i]) & 0xf
+}
+
+func bbPossible(bb *BitBoard, i int) int {
+	return int(bb[i]>>4) & 0x1ff
+}
+
+func bbPCount(bb *BitBoard, i int) int {
+	return bitcount(bbPossible(bb, i))
+}
+
+// bbGivenCount counts cells that already hold a value.
+func bbGivenCount(bb *BitBoard) (givens int) {
+	for i := 0; i < 81; i++ {
+		if bbValue(bb, i) != 0 {
+			givens++
+		}
+	}
+	return
+}
+
+func bbSlotIsSet(bb *BitBoard, i int, p uint) bool {
+	bit := uint16(1<<(p-1)) << 4
+	return bb[i]&bit != 0
+}
+
+func bbSetValue(bb *BitBoard, i, v int) {
+	if v == 0 {
+		bb[i] = 0x1ff << 4
+		return
+	}
+	bb[i] = uint16(1<<uint(v-1))<<4 | uint16(v)
+}
+
+func bbClearSlot(bb *BitBoard, i int, p uint) {
+	bit := uint16(1<<(p-1)) << 4
+	bb[i] &^= bit
+}
+
+// boardToBitBoard copies the values and candidate masks already present
+// in puzzle into a BitBoard; it doesn't recompute anything.
+func boardToBitBoard(puzzle board) BitBoard {
+	var bb BitBoard
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			i := r*9 + c
+			if v := puzzle[r][c].value(); v != 0 {
+				bbSetValue(&bb, i, v)
+			} else {
+				bb[i] = uint16(puzzle[r][c].possible()) << 4
+			}
+		}
+	}
+	return bb
+}
+
+// toBoard allocates a fresh board (the public, *cell-based API the rest
+// of the package uses) from bb.
+func (bb BitBoard) toBoard() board {
+	var puzzle board
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			i := r*9 + c
+			nc := new(cell)
+			if v := bbValue(&bb, i); v != 0 {
+				nc.setvalue(v)
+			} else {
+				*nc = cell(bbPossible(&bb, i) << 5)
+			}
+			puzzle[r][c] = nc
+		}
+	}
+	return puzzle
+}
+
+// unitReduce clears, from unit's first (self) cell, every value already
+// held by another cell in the unit, and returns how many candidates the
+// self cell has left.
+func unitReduce(bb *BitBoard, u [9]uint8) int {
+	self := int(u[0])
+	for _, idx := range u[1:] {
+		if v := bbValue(bb, int(idx)); v != 0 {
+			bbClearSlot(bb, self, uint(v))
+		}
+	}
+	return bbPCount(bb, self)
+}
+
+// unitHasVal reports whether some cell in unit other than the self cell
+// could still hold value.
+func unitHasVal(bb *BitBoard, u [9]uint8, value int) bool {
+	for _, idx := range u[1:] {
+		i := int(idx)
+		if bbValue(bb, i) == value || bbSlotIsSet(bb, i, uint(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// unitUniqueSlot finds candidate bits unique to the self cell within
+// unit; checkzeros widens the scan to cells that don't have a value yet.
+func unitUniqueSlot(bb *BitBoard, u [9]uint8, checkzeros bool) int {
+	self := int(u[0])
+	pos := bbPossible(bb, self)
+	val := pos
+	for _, idx := range u[1:] {
+		i := int(idx)
+		if checkzeros || bbValue(bb, i) != 0 {
+			val ^= bbPossible(bb, i)
+			val &= pos
+		}
+		if val == 0 {
+			return 0
+		}
+	}
+	return pos
+}
+
+// unitFindMatching looks for another unknown cell in unit whose
+// candidates are exactly the self cell's two candidates.
+func unitFindMatching(bb *BitBoard, u [9]uint8) (bool, int) {
+	self := int(u[0])
+	openslots := bbPossible(bb, self)
+	if bitcount(openslots) != 2 {
+		return false, -1
+	}
+	for _, idx := range u[1:] {
+		i := int(idx)
+		if bbValue(bb, i) == 0 && bbPossible(bb, i) == openslots {
+			return true, i
+		}
+	}
+	return false, -1
+}
+
+// unitRemoveSlots clears the two candidates shared by cells ea and eb
+// from every other unknown cell in unit, assigning any cell that's left
+// with a single candidate.
+func unitRemoveSlots(bb *BitBoard, u [9]uint8, ea, eb int) bool {
+	vals := bitvalues(bbPossible(bb, ea))
+
+	found := false
+	for _, idx := range u {
+		i := int(idx)
+		if i == ea || i == eb || bbValue(bb, i) != 0 {
+			continue
+		}
+		for _, v := range vals {
+			if bbSlotIsSet(bb, i, uint(v)) {
+				bbClearSlot(bb, i, uint(v))
+			}
+		}
+		if bbPCount(bb, i) == 1 {
+			bbSetValue(bb, i, bitvalue(bbPossible(bb, i)))
+			found = true
+		}
+	}
+	return found
+}
+
+// checkCellBB narrows cell i's candidates against its row, column and box
+// (the precomputed unitTable entries), then acts on what is left: it
+// assigns a lone candidate, clears a naked pair's two values from the
+// rest of a unit, or assigns a candidate no peer can still hold.
+// impossible is set when no candidate is left.
+func checkCellBB(bb *BitBoard, i int) (changed, impossible bool) {
+	openslots := bbPossible(bb, i)
+	possibles := bitcount(openslots)
+	if possibles < 2 {
+		return
+	}
+
+	rowUnit := unitTable[i][0]
+	colUnit := unitTable[i][1]
+	boxUnit := unitTable[i][2]
+
+	possibles = unitReduce(bb, boxUnit)
+	if possibles > 1 {
+		possibles = unitReduce(bb, colUnit)
+	}
+	if possibles > 1 {
+		possibles = unitReduce(bb, rowUnit)
+	}
+
+	openslots = bbPossible(bb, i)
+
+	switch possibles {
+	case 0:
+		impossible = true
+		return
+
+	case 1:
+		bbSetValue(bb, i, bitvalue(openslots))
+		changed = true
+		return
+
+	case 2:
+		if ok, j := unitFindMatching(bb, rowUnit); ok {
+			if unitRemoveSlots(bb, rowUnit, i, j) {
+				changed = true
+				return
+			}
+		}
+		if ok, j := unitFindMatching(bb, colUnit); ok {
+			if unitRemoveSlots(bb, colUnit, i, j) {
+				changed = true
+				return
+			}
+		}
+		if ok, j := unitFindMatching(bb, boxUnit); ok {
+			if unitRemoveSlots(bb, boxUnit, i, j) {
+				changed = true
+				return
+			}
+		}
+
+	default:
+		pb := bbPossible(bb, i)
+		for n := 1; pb != 0; n++ {
+			if pb&1 == 1 && !unitHasVal(bb, rowUnit, n) && !unitHasVal(bb, colUnit, n) && !unitHasVal(bb, boxUnit, n) {
+				bbSetValue(bb, i, n)
+				changed = true
+				return
+			}
+			pb >>= 1
+		}
+
+		for _, checkzeros := range []bool{false, true} {
+			ur := unitUniqueSlot(bb, rowUnit, checkzeros)
+			uc := unitUniqueSlot(bb, colUnit, checkzeros)
+			ub := unitUniqueSlot(bb, boxUnit, checkzeros)
+			bit := ur & uc & ub
+			if bitcount(bit) == 1 {
+				bbSetValue(bb, i, bitvalue(bit))
+				changed = true
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// findOneBB runs checkCellBB over the cells in order and stops at the
+// first one that changes or turns out impossible.
+func findOneBB(bb *BitBoard) (changed, impossible bool) {
+	for i := 0; i < 81; i++ {
+		changed, impossible = checkCellBB(bb, i)
+		if changed || impossible {
+			return
+		}
+	}
+	return false, false
+}
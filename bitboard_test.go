@@ -0,0 +1,97 @@
+package main
+
+import "testing"
+
+// blankBitBoard returns a BitBoard with every cell unknown and every
+// candidate open, mirroring newBlankBoard's cell.setvalue(0) state.
+func blankBitBoard() BitBoard {
+	var bb BitBoard
+	for i := range bb {
+		bbSetValue(&bb, i, 0)
+	}
+	return bb
+}
+
+// TestCheckCellBBNakedPair plants a naked pair {1,2} at (r0,c0) and
+// (r0,c1); a third cell in the row can hold {1,2,3}, and the rest of
+// the row is wide open. One findOneBB pass should recognize the pair,
+// pin the third cell to 3 (its only remaining candidate) and strip 1
+// and 2 from the rest of the row.
+func TestCheckCellBBNakedPair(t *testing.T) {
+	bb := blankBitBoard()
+	bb[0] = uint16(0x3) << 4 // (r0,c0): candidates {1,2}
+	bb[1] = uint16(0x3) << 4 // (r0,c1): candidates {1,2}
+	bb[2] = uint16(0x7) << 4 // (r0,c2): candidates {1,2,3}
+
+	changed, impossible := findOneBB(&bb)
+	if impossible {
+		t.Fatal("findOneBB reported impossible")
+	}
+	if !changed {
+		t.Fatal("findOneBB made no change, want the naked pair to fire")
+	}
+
+	if v := bbValue(&bb, 2); v != 3 {
+		t.Errorf("(r0,c2) value = %d, want 3 (only candidate left once the pair clears 1,2)", v)
+	}
+	if mask := bbPossible(&bb, 8); mask&0x3 != 0 {
+		t.Errorf("(r0,c8) candidate mask = %09b, want bits for 1,2 cleared by the naked pair", mask)
+	}
+}
+
+// TestCheckCellBBHiddenSingle plants a hidden single: value 5 is
+// cleared from every other cell in (r0,c4)'s row, column and box, so
+// it's the only cell left that can hold 5 even though it still has
+// every other candidate open. One findOneBB pass should resolve it.
+func TestCheckCellBBHiddenSingle(t *testing.T) {
+	bb := blankBitBoard()
+	const self = 4 // (r0, c4)
+
+	clear := map[int]bool{}
+	for c := 0; c < 9; c++ {
+		if idx := 0*9 + c; idx != self {
+			clear[idx] = true
+		}
+	}
+	for r := 0; r < 9; r++ {
+		if idx := r*9 + 4; idx != self {
+			clear[idx] = true
+		}
+	}
+	for r := 0; r < 3; r++ {
+		for c := 3; c < 6; c++ {
+			if idx := r*9 + c; idx != self {
+				clear[idx] = true
+			}
+		}
+	}
+	for idx := range clear {
+		bbClearSlot(&bb, idx, 5)
+	}
+
+	changed, impossible := findOneBB(&bb)
+	if impossible {
+		t.Fatal("findOneBB reported impossible")
+	}
+	if !changed {
+		t.Fatal("findOneBB made no change, want the hidden single to fire")
+	}
+
+	if v := bbValue(&bb, self); v != 5 {
+		t.Errorf("(r0,c4) value = %d, want 5 (the only cell in its row/col/box that can still hold 5)", v)
+	}
+}
+
+// TestFindOneBBImpossible plants a cell whose only two candidates are
+// both already taken by other givens in its box, leaving it with none.
+func TestFindOneBBImpossible(t *testing.T) {
+	bb := blankBitBoard()
+	bb[0] = uint16(0x3) << 4 // (r0,c0): candidates {1,2}
+	bbSetValue(&bb, 1, 1)    // (r0,c1): given 1, same box as (r0,c0)
+	bbSetValue(&bb, 2, 2)    // (r0,c2): given 2, same box as (r0,c0)
+
+	_, impossible := findOneBB(&bb)
+	if !impossible {
+		t.Error("findOneBB reported possible for a cell both its remaining candidates were taken from, want impossible")
+	}
+}
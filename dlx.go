@@ -0,0 +1,275 @@
+package main
+
+import "math/rand"
+
+// Sudoku as exact cover, solved with Knuth's Dancing Links (Algorithm
+// X). There are 324 constraint columns (81 cell-filled, 81 row-has-digit,
+// 81 col-has-digit, 81 box-has-digit) and up to 729 candidate rows, one
+// per (r, c, v) triple. Picking a set of rows that covers every column
+// exactly once is a solved puzzle.
+
+const (
+	dlxCells = 81
+	dlxCols  = 4 * dlxCells
+	dlxRows  = 729
+)
+
+// dlxNode is both a matrix cell and, via column, the header of its
+// column; header nodes and column objects are the same struct so the
+// circular lists can be walked uniformly.
+type dlxNode struct {
+	left, right, up, down *dlxNode
+	column                *dlxColumn
+	r, c, v               int // candidate this row represents
+}
+
+type dlxColumn struct {
+	dlxNode
+	size int
+}
+
+// dlxMatrix is a fully built exact-cover matrix for an empty board; the
+// givens of a particular puzzle are applied by covering their rows.
+type dlxMatrix struct {
+	root *dlxColumn
+	cols [dlxCols]*dlxColumn
+	rows [9][9][9]*dlxNode // rows[r][c][v-1] -> leftmost node of that candidate row
+	rng  *rand.Rand        // non-nil to randomize column/row choice during search; see generate.go
+}
+
+func colIndex(r, c, v int) (cell, row, col, box int) {
+	b := (r/3)*3 + c/3
+	cell = r*9 + c
+	row = dlxCells + r*9 + (v - 1)
+	col = dlxCells*2 + c*9 + (v - 1)
+	box = dlxCells*3 + b*9 + (v - 1)
+	return
+}
+
+func newDLXMatrix() *dlxMatrix {
+	m := &dlxMatrix{}
+	m.root = &dlxColumn{}
+	m.root.left, m.root.right = &m.root.dlxNode, &m.root.dlxNode
+
+	for i := 0; i < dlxCols; i++ {
+		col := &dlxColumn{}
+		col.column = col
+		col.up, col.down = &col.dlxNode, &col.dlxNode
+		// link at the end of the header row
+		last := m.root.left
+		last.right = &col.dlxNode
+		col.left = last
+		col.right = &m.root.dlxNode
+		m.root.left = &col.dlxNode
+		m.cols[i] = col
+	}
+
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			for v := 1; v <= 9; v++ {
+				cell, row, col, box := colIndex(r, c, v)
+				idx := []int{cell, row, col, box}
+
+				var first, prev *dlxNode
+				for _, ci := range idx {
+					n := &dlxNode{r: r, c: c, v: v, column: m.cols[ci]}
+					column := m.cols[ci]
+					last := column.up
+					last.down = n
+					n.up = last
+					n.down = &column.dlxNode
+					column.up = n
+					column.size++
+
+					if first == nil {
+						first = n
+						n.left, n.right = n, n
+					} else {
+						n.left = prev
+						prev.right = n
+						n.right = first
+						first.left = n
+					}
+					prev = n
+				}
+				m.rows[r][c][v-1] = first
+			}
+		}
+	}
+	return m
+}
+
+func (col *dlxColumn) cover() {
+	col.right.left = col.left
+	col.left.right = col.right
+	for row := col.down; row != &col.dlxNode; row = row.down {
+		for n := row.right; n != row; n = n.right {
+			n.down.up = n.up
+			n.up.down = n.down
+			n.column.size--
+		}
+	}
+}
+
+func (col *dlxColumn) uncover() {
+	for row := col.up; row != &col.dlxNode; row = row.up {
+		for n := row.left; n != row; n = n.left {
+			n.column.size++
+			n.down.up = n
+			n.up.down = n
+		}
+	}
+	col.right.left = &col.dlxNode
+	col.left.right = &col.dlxNode
+}
+
+// selectRow commits to a candidate row (a given, or a search guess) by
+// covering its column and every column its siblings satisfy.
+func selectRow(row *dlxNode) {
+	row.column.cover()
+	for n := row.right; n != row; n = n.right {
+		n.column.cover()
+	}
+}
+
+func deselectRow(row *dlxNode) {
+	for n := row.left; n != row; n = n.left {
+		n.column.uncover()
+	}
+	row.column.uncover()
+}
+
+// search runs Algorithm X: repeatedly choose the column with the fewest
+// candidates, try each of its rows, and recurse. It stops as soon as
+// `max` solutions have been found.
+func (m *dlxMatrix) search(solution []*dlxNode, found *[][9][9]int, max int) bool {
+	if m.root.right == &m.root.dlxNode {
+		*found = append(*found, solvedBoard(solution))
+		return len(*found) >= max
+	}
+
+	col := m.chooseColumn()
+	col.cover()
+
+	for _, row := range m.rowsOf(col) {
+		solution = append(solution, row)
+		for n := row.right; n != row; n = n.right {
+			n.column.cover()
+		}
+
+		if m.search(solution, found, max) {
+			return true
+		}
+
+		for n := row.left; n != row; n = n.left {
+			n.column.uncover()
+		}
+		solution = solution[:len(solution)-1]
+	}
+
+	col.uncover()
+	return false
+}
+
+// chooseColumn picks the column with the fewest remaining candidates;
+// with m.rng set it breaks ties randomly instead of by header order,
+// which is what lets generate.go produce varied complete grids.
+func (m *dlxMatrix) chooseColumn() *dlxColumn {
+	var tied []*dlxColumn
+	best := -1
+	for n := m.root.right; n != &m.root.dlxNode; n = n.right {
+		switch {
+		case best == -1 || n.column.size < best:
+			best = n.column.size
+			tied = []*dlxColumn{n.column}
+		case n.column.size == best:
+			tied = append(tied, n.column)
+		}
+	}
+	if m.rng != nil && len(tied) > 1 {
+		return tied[m.rng.Intn(len(tied))]
+	}
+	return tied[0]
+}
+
+// rowsOf lists col's candidate rows, in random order when m.rng is set.
+func (m *dlxMatrix) rowsOf(col *dlxColumn) []*dlxNode {
+	var rows []*dlxNode
+	for row := col.down; row != &col.dlxNode; row = row.down {
+		rows = append(rows, row)
+	}
+	if m.rng != nil {
+		m.rng.Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+	}
+	return rows
+}
+
+func solvedBoard(solution []*dlxNode) (b [9][9]int) {
+	for _, n := range solution {
+		b[n.r][n.c] = n.v
+	}
+	return
+}
+
+// dlxFromBitBoard builds the exact-cover matrix for bb and covers the
+// rows for every given cell, leaving only the empty cells to search.
+func dlxFromBitBoard(bb *BitBoard) *dlxMatrix {
+	m := newDLXMatrix()
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			if v := bbValue(bb, r*9+c); v != 0 {
+				selectRow(m.rows[r][c][v-1])
+			}
+		}
+	}
+	return m
+}
+
+// CountSolutionsBB reports how many distinct solutions bb has, stopping
+// as soon as max have been found; callers that only care whether a
+// puzzle is uniquely solvable can pass max=2.
+func CountSolutionsBB(bb *BitBoard, max int) int {
+	m := dlxFromBitBoard(bb)
+	var found [][9][9]int
+	m.search(nil, &found, max)
+	return len(found)
+}
+
+// CountSolutions is CountSolutionsBB's board-based compatibility shim.
+func CountSolutions(puzzle board, max int) int {
+	bb := boardToBitBoard(puzzle)
+	return CountSolutionsBB(&bb, max)
+}
+
+// dlxSolveBB finds a single solution to bb using Algorithm X and applies
+// it on top of a copy of bb (a plain array copy -- see BitBoard);
+// impossible is true if no candidate rows remain to cover some empty
+// cell or no cover exists.
+func dlxSolveBB(bb BitBoard) (out BitBoard, impossible bool) {
+	out = bb
+	m := dlxFromBitBoard(&out)
+
+	var found [][9][9]int
+	m.search(nil, &found, 1)
+	if len(found) == 0 {
+		return out, true
+	}
+
+	sol := found[0]
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			i := r*9 + c
+			if bbValue(&out, i) == 0 {
+				bbSetValue(&out, i, sol[r][c])
+			}
+		}
+	}
+	return out, false
+}
+
+// dlxSolve is dlxSolveBB's board-based compatibility shim.
+func dlxSolve(puzzle board) (pz board, impossible bool) {
+	bb := boardToBitBoard(puzzle)
+	bb, impossible = dlxSolveBB(bb)
+	return bb.toBoard(), impossible
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestCountSolutionsUniquePuzzle(t *testing.T) {
+	var puzzle board
+	if err := puzzle.Unmarshal([]byte(
+		"53..7....6..195....98....6.8...6...34..8.3..17...2...6.6....28....419..5....8..79",
+	), fmtLine); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if n := CountSolutions(puzzle, 2); n != 1 {
+		t.Errorf("CountSolutions(unique puzzle, 2) = %d, want 1", n)
+	}
+}
+
+func TestCountSolutionsBlankBoardHasMany(t *testing.T) {
+	puzzle := newBlankBoard()
+
+	if n := CountSolutions(puzzle, 2); n != 2 {
+		t.Errorf("CountSolutions(blank board, 2) = %d, want 2 (stops early on an ambiguous puzzle)", n)
+	}
+}
+
+func TestDlxSolveMatchesKnownSolution(t *testing.T) {
+	var puzzle board
+	if err := puzzle.Unmarshal([]byte(
+		"53..7....6..195....98....6.8...6...34..8.3..17...2...6.6....28....419..5....8..79",
+	), fmtLine); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	want := "534678912672195348198342567859761423426853791713924856961537284287419635345286179"
+
+	solved, impossible := dlxSolve(puzzle)
+	if impossible {
+		t.Fatal("dlxSolve reported impossible for a solvable puzzle")
+	}
+
+	got, err := solved.Marshal(fmtLine)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("dlxSolve solution = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// supported values for -format and -output
+const (
+	fmtAuto = "auto"
+	fmtRow  = "row"
+	fmtCol  = "col"
+	fmtLine = "line"
+	fmtSDK  = "sdk"
+	fmtJSON = "json"
+	fmtGrid = "grid" // output only: pretty 3x3-boxed ASCII grid
+)
+
+// detectFormat sniffs description and guesses which of the supported
+// input formats it's in.
+func detectFormat(description string) string {
+	trimmed := strings.TrimSpace(description)
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		return fmtJSON
+	case strings.ContainsAny(trimmed, "|+-") && strings.Contains(trimmed, "\n"):
+		return fmtSDK
+	case len(strings.Fields(trimmed)) >= 81:
+		return fmtRow
+	case !strings.Contains(trimmed, "\n") && len(digitsOnly(trimmed)) >= 81:
+		return fmtLine
+	default:
+		return fmtRow
+	}
+}
+
+func digitsOnly(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if (r >= '0' && r <= '9') || r == '.' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func newBlankBoard() (puzzle board) {
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			nc := new(cell)
+			nc.setvalue(0)
+			puzzle[r][c] = nc
+		}
+	}
+	return
+}
+
+// Unmarshal parses data according to format (one of fmtAuto, fmtRow,
+// fmtCol, fmtLine, fmtSDK, fmtJSON) into the receiver.
+func (puzzle *board) Unmarshal(data []byte, format string) error {
+	description := string(data)
+
+	if format == "" || format == fmtAuto {
+		format = detectFormat(description)
+	}
+
+	var p board
+	var err error
+	switch format {
+	case fmtRow:
+		p, err = parseRowColumn(description)
+	case fmtCol:
+		p, err = parseColumnRow(description)
+	case fmtLine:
+		p, err = parseLine(description)
+	case fmtSDK:
+		p, err = parseSDK(description)
+	case fmtJSON:
+		p, err = parseJSON(data)
+	default:
+		return fmt.Errorf("unknown input format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	*puzzle = p
+	return nil
+}
+
+// parseLine parses the single-line 81-character format: digits 1-9 with
+// '.' or '0' for blanks; newlines, if any, are ignored.
+func parseLine(description string) (puzzle board, err error) {
+	puzzle = newBlankBoard()
+	digits := digitsOnly(description)
+	if len(digits) < 81 {
+		return puzzle, fmt.Errorf("line format needs 81 digits, got %d", len(digits))
+	}
+
+	for i, x := range digits[:81] {
+		r, c := i/9, i%9
+		switch x {
+		case '.', '0':
+			puzzle[r][c].setvalue(0)
+		case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			puzzle[r][c].setvalue(int(x) - int('0'))
+		default:
+			return puzzle, fmt.Errorf("bad puzzle value at position %d: %q", i, x)
+		}
+	}
+	return puzzle, nil
+}
+
+// parseSDK parses the SDK/SadMan Sudoku text format: a 9x9 grid drawn
+// with '|' column separators and '+'/'-' box dividers, '.' or '0' for
+// blanks, and '#' comment lines.
+func parseSDK(description string) (puzzle board, err error) {
+	puzzle = newBlankBoard()
+
+	r := 0
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.ContainsAny(trimmed, "+-") && !strings.ContainsAny(trimmed, "123456789.0") {
+			continue // box divider, e.g. "+---+---+---+"
+		}
+		if r >= 9 {
+			return puzzle, fmt.Errorf("too many rows in sdk input")
+		}
+
+		c := 0
+		for _, x := range line {
+			switch x {
+			case '.', '0':
+				if c >= 9 {
+					return puzzle, fmt.Errorf("too many columns in sdk row %d", r)
+				}
+				puzzle[r][c].setvalue(0)
+				c++
+			case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				if c >= 9 {
+					return puzzle, fmt.Errorf("too many columns in sdk row %d", r)
+				}
+				puzzle[r][c].setvalue(int(x) - int('0'))
+				c++
+			case '|', ' ':
+				// separators are decorative
+			default:
+				return puzzle, fmt.Errorf("bad sdk character %q in row %d", x, r)
+			}
+		}
+		if c != 9 {
+			return puzzle, fmt.Errorf("not enough columns in sdk row %d", r)
+		}
+		r++
+	}
+	if r != 9 {
+		return puzzle, fmt.Errorf("not enough rows in sdk input")
+	}
+	return puzzle, nil
+}
+
+type jsonGiven struct {
+	R int `json:"r"`
+	C int `json:"c"`
+	V int `json:"v"`
+}
+
+type jsonPuzzle struct {
+	Givens []jsonGiven `json:"givens"`
+}
+
+// parseJSON parses the {"givens":[{"r":0,"c":0,"v":5},...]} format.
+func parseJSON(data []byte) (puzzle board, err error) {
+	puzzle = newBlankBoard()
+
+	var jp jsonPuzzle
+	if err := json.Unmarshal(data, &jp); err != nil {
+		return puzzle, err
+	}
+
+	for _, g := range jp.Givens {
+		if g.R < 0 || g.R > 8 || g.C < 0 || g.C > 8 || g.V < 1 || g.V > 9 {
+			return puzzle, fmt.Errorf("bad given %+v", g)
+		}
+		puzzle[g.R][g.C].setvalue(g.V)
+	}
+	return puzzle, nil
+}
+
+// Marshal renders puzzle in format (fmtRow, fmtCol, fmtLine, fmtSDK,
+// fmtJSON or fmtGrid).
+func (puzzle board) Marshal(format string) ([]byte, error) {
+	switch format {
+	case fmtRow:
+		return puzzle.marshalRow(), nil
+	case fmtCol:
+		return puzzle.marshalCol(), nil
+	case fmtLine:
+		return puzzle.marshalLine(), nil
+	case fmtSDK:
+		return puzzle.marshalSDK(), nil
+	case fmtJSON:
+		return puzzle.marshalJSON()
+	case fmtGrid:
+		return puzzle.marshalGrid(), nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+func digit(v int) byte {
+	if v == 0 {
+		return '.'
+	}
+	return byte('0' + v)
+}
+
+func (puzzle board) marshalRow() []byte {
+	var buf bytes.Buffer
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			if c > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteByte(digit(puzzle[r][c].value()))
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+func (puzzle board) marshalCol() []byte {
+	var buf bytes.Buffer
+	for c := 0; c < 9; c++ {
+		for r := 0; r < 9; r++ {
+			buf.WriteByte(digit(puzzle[r][c].value()))
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+func (puzzle board) marshalLine() []byte {
+	var buf bytes.Buffer
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			buf.WriteByte(digit(puzzle[r][c].value()))
+		}
+	}
+	return buf.Bytes()
+}
+
+func (puzzle board) marshalSDK() []byte {
+	var buf bytes.Buffer
+	divider := "+-------+-------+-------+\n"
+	for r := 0; r < 9; r++ {
+		if r%3 == 0 {
+			buf.WriteString(divider)
+		}
+		for c := 0; c < 9; c++ {
+			if c%3 == 0 {
+				buf.WriteByte('|')
+			}
+			buf.WriteByte(' ')
+			buf.WriteByte(digit(puzzle[r][c].value()))
+		}
+		buf.WriteString(" |\n")
+	}
+	buf.WriteString(divider)
+	return buf.Bytes()
+}
+
+func (puzzle board) marshalJSON() ([]byte, error) {
+	var jp jsonPuzzle
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			if v := puzzle[r][c].value(); v != 0 {
+				jp.Givens = append(jp.Givens, jsonGiven{R: r, C: c, V: v})
+			}
+		}
+	}
+	return json.Marshal(jp)
+}
+
+// marshalGrid renders a pretty-printed 3x3-boxed ASCII grid, in the
+// style of the Rosetta Code ALGOL 68 example.
+func (puzzle board) marshalGrid() []byte {
+	var buf bytes.Buffer
+	rule := strings.Repeat("-", 25)
+	for r := 0; r < 9; r++ {
+		if r%3 == 0 {
+			buf.WriteString(rule)
+			buf.WriteByte('\n')
+		}
+		for c := 0; c < 9; c++ {
+			if c%3 == 0 {
+				buf.WriteString("| ")
+			}
+			buf.WriteByte(digit(puzzle[r][c].value()))
+			buf.WriteByte(' ')
+		}
+		buf.WriteString("|\n")
+	}
+	buf.WriteString(rule)
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+const sampleLine = "53..7....6..195....98....6.8...6...34..8.3..17...2...6.6....28....419..5....8..79"
+
+func TestMarshalUnmarshalRoundTrip(t *testing.T) {
+	var want board
+	if err := want.Unmarshal([]byte(sampleLine), fmtLine); err != nil {
+		t.Fatalf("Unmarshal(fmtLine): %v", err)
+	}
+
+	for _, format := range []string{fmtRow, fmtCol, fmtLine, fmtSDK, fmtJSON} {
+		data, err := want.Marshal(format)
+		if err != nil {
+			t.Fatalf("Marshal(%s): %v", format, err)
+		}
+
+		var got board
+		if err := got.Unmarshal(data, format); err != nil {
+			t.Fatalf("Unmarshal(%s): %v\ndata:\n%s", format, err, data)
+		}
+
+		gotLine, err := got.Marshal(fmtLine)
+		if err != nil {
+			t.Fatalf("Marshal(fmtLine) after round trip through %s: %v", format, err)
+		}
+		if string(gotLine) != sampleLine {
+			t.Errorf("round trip through %s = %q, want %q", format, gotLine, sampleLine)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	colMajor := "53..7....\n6..195...\n.98....6.\n8...6...3\n4..8.3..1\n7...2...6\n.6....28.\n...419..5\n....8..79"
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"single line digits", sampleLine, fmtLine},
+		{"json givens", `{"givens":[{"r":0,"c":0,"v":5}]}`, fmtJSON},
+		{"sdk separators", "+---+\n|1|.|\n+---+", fmtSDK},
+		// a multi-line, unseparated grid is not detected as line: only
+		// the absence of a newline means "line", so it falls through
+		// to the row default.
+		{"multi-line col digits", colMajor, fmtRow},
+	}
+
+	for _, tt := range tests {
+		if got := detectFormat(tt.in); got != tt.want {
+			t.Errorf("detectFormat(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
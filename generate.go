@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// symmetry patterns supported by -symmetry
+const (
+	symNone       = "none"
+	symRotational = "rotational"
+)
+
+// difficulty ratings, derived from which rule engine techniques (see
+// rules.go) were needed to reach a full solution.
+const (
+	diffEasy   = "easy"
+	diffMedium = "medium"
+	diffHard   = "hard"
+	diffEvil   = "evil"
+)
+
+// runGenerate implements the `ukodus generate` subcommand: fill a board
+// with the DLX solver run on a shuffled matrix to get a random complete
+// solution, then remove clues (respecting -symmetry) for as long as the
+// puzzle stays uniquely solvable, stopping at -clues or sooner.
+func runGenerate(args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	clues := fs.Int("clues", 28, "target number of givens to leave in the puzzle")
+	symmetry := fs.String("symmetry", symRotational, "clue removal pattern: none|rotational")
+	wantDifficulty := fs.String("difficulty", "", "keep generating until the puzzle rates at this difficulty: easy|medium|hard|evil")
+	output := fs.String("output", fmtRow, "format to print the puzzle in: row|col|line|sdk|json")
+	fs.Parse(args)
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for {
+		solution := generateSolution(rng)
+		puzzle := removeClues(rng, solution, *symmetry, *clues)
+		difficulty := rate(puzzle)
+
+		if *wantDifficulty != "" && difficulty != *wantDifficulty {
+			continue
+		}
+
+		out, err := puzzle.Marshal(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(out)
+		fmt.Printf("clues: %d, difficulty: %s\n", givenCount(puzzle), difficulty)
+		return
+	}
+}
+
+// generateSolution fills a blank board with Algorithm X, randomizing
+// column and row choice so that repeated calls yield different grids.
+func generateSolution(rng *rand.Rand) board {
+	m := newDLXMatrix()
+	m.rng = rng
+
+	var found [][9][9]int
+	m.search(nil, &found, 1)
+
+	puzzle := newBlankBoard()
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			puzzle[r][c].setvalue(found[0][r][c])
+		}
+	}
+	return puzzle
+}
+
+// symmetryPartner returns the cell that must be cleared alongside (r, c)
+// to preserve the requested symmetry.
+func symmetryPartner(symmetry string, r, c int) (int, int) {
+	if symmetry == symRotational {
+		return 8 - r, 8 - c
+	}
+	return r, c
+}
+
+// removeClues clears clues from solution, shuffled for randomness and
+// paired up per symmetry, stopping as soon as no more can be removed
+// without losing uniqueness or the target clue count is reached. It
+// works directly on a BitBoard so every trial removal -- and the
+// CountSolutionsBB check that follows it -- costs no allocation; a
+// rejected removal is undone in place instead of restoring from a
+// replicated copy.
+func removeClues(rng *rand.Rand, solution board, symmetry string, targetClues int) board {
+	bb := boardToBitBoard(solution)
+
+	for _, i := range rng.Perm(81) {
+		if bbGivenCount(&bb) <= targetClues {
+			break
+		}
+
+		r, c := i/9, i%9
+		idx := r*9 + c
+		if bbValue(&bb, idx) == 0 {
+			continue
+		}
+		r2, c2 := symmetryPartner(symmetry, r, c)
+		idx2 := r2*9 + c2
+
+		saved1, saved2 := bb[idx], bb[idx2]
+		bbSetValue(&bb, idx, 0)
+		bbSetValue(&bb, idx2, 0)
+
+		if CountSolutionsBB(&bb, 2) != 1 {
+			bb[idx], bb[idx2] = saved1, saved2
+		}
+	}
+
+	return bb.toBoard()
+}
+
+func givenCount(puzzle board) int {
+	return 81 - unknownCount(puzzle)
+}
+
+// rate classifies puzzle by the hardest technique the rule engine
+// needed to solve it without guessing: Easy needs only the elimination
+// pass, Medium needs naked/hidden subsets, Hard needs pointing
+// pairs/X-Wing/Swordfish, and Evil needs the DLX brute-force fallback.
+func rate(puzzle board) string {
+	pz, _, impossible := elimination(puzzle)
+	if impossible {
+		return diffEvil
+	}
+
+	var deductions []Elimination
+	pz, deductions = humansolve(pz)
+
+	usedSubset, usedAdvanced := false, false
+	for _, e := range deductions {
+		switch e.Rule {
+		case "naked-pair", "naked-triple", "naked-quad", "hidden-pair", "hidden-triple":
+			usedSubset = true
+		case "pointing-pair", "x-wing", "swordfish":
+			usedAdvanced = true
+		}
+	}
+
+	if unknownCount(pz) != 0 {
+		return diffEvil
+	}
+	if usedAdvanced {
+		return diffHard
+	}
+	if usedSubset {
+		return diffMedium
+	}
+	return diffEasy
+}
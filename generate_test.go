@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestRateEasyPuzzle(t *testing.T) {
+	if got := rate(lineBoard(t, sampleLine)); got != diffEasy {
+		t.Errorf("rate(sampleLine) = %q, want %q", got, diffEasy)
+	}
+}
+
+func TestRateEvilInfeasiblePuzzle(t *testing.T) {
+	// same dead-cell puzzle as TestValidateFeasibleDeadCell: elimination
+	// itself must report impossible, which rate treats as evil.
+	line := ".23456789" + "1........" + strings.Repeat(".........", 7)
+	if got := rate(lineBoard(t, line)); got != diffEvil {
+		t.Errorf("rate(dead-cell puzzle) = %q, want %q", got, diffEvil)
+	}
+}
+
+func TestRemoveCluesPreservesUniqueness(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	solution := generateSolution(rng)
+	puzzle := removeClues(rng, solution, symRotational, 30)
+
+	if n := givenCount(puzzle); n > 30 {
+		t.Errorf("givenCount(puzzle) = %d, want <= 30", n)
+	}
+	if err := Validate(puzzle); err != nil {
+		t.Errorf("Validate(removeClues result) = %v, want nil", err)
+	}
+	if n := CountSolutions(puzzle, 2); n != 1 {
+		t.Errorf("CountSolutions(removeClues result, 2) = %d, want 1 (uniqueness must survive clue removal)", n)
+	}
+}
+
+func TestRateMediumPuzzle(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	solution := generateSolution(rng)
+	puzzle := removeClues(rng, solution, symNone, 26)
+
+	if got := rate(puzzle); got != diffMedium {
+		t.Errorf("rate(puzzle) = %q, want %q", got, diffMedium)
+	}
+}
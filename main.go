@@ -17,7 +17,6 @@ import (
 // cell type uses the lowest 5 bits for value of the cell, 0 meaning
 // unknown and the next lowest 9 bits for possible values
 type cell int
-type tuple []*cell
 type board [9][9]*cell
 
 func (c *cell) value() int {
@@ -58,17 +57,6 @@ func (c *cell) clearslot(p uint) {
 	*c &= cell(bitoff | 0x1f)
 }
 
-func (c *cell) assign(rhs *cell) {
-	*c = *rhs
-}
-
-func parseboard(description string, p9format bool) (board, error) {
-	if p9format {
-		return parseColumnRow(description)
-	}
-	return parseRowColumn(description)
-}
-
 func parseRowColumn(description string) (puzzle board, err error) {
 	lines := strings.Split(description, "\n")
 	if len(lines) < 9 {
@@ -83,8 +71,8 @@ func parseRowColumn(description string) (puzzle board, err error) {
 			err = errors.New(fmt.Sprintf("not enough columns in line %d", r))
 			return
 		}
-		// TODO: more input checking: check for duplicate values
-		// for each row, column and box
+		// duplicate values and feasibility are checked by Validate
+		// in validate.go, once the whole board is parsed
 		for c, x := range cols[:9] {
 			switch x[0] {
 			case '.', '_', '0':
@@ -119,8 +107,8 @@ func parseColumnRow(description string) (puzzle board, err error) {
 			err = errors.New(fmt.Sprintf("not enough rows in line %d", c))
 			return
 		}
-		// TODO: more input checking: check for duplicate values
-		// for each row, column and box
+		// duplicate values and feasibility are checked by Validate
+		// in validate.go, once the whole board is parsed
 		for r, x := range rows[:9] {
 			switch x[0] {
 			case '.', '_':
@@ -141,21 +129,19 @@ func parseColumnRow(description string) (puzzle board, err error) {
 	return
 }
 
-func replicate(p1 board) (p2 board) {
-	for r := 0; r < 9; r++ {
-		for c := 0; c < 9; c++ {
-			nc := new(cell)
-			nc.assign(p1[r][c])
-			p2[r][c] = nc
-		}
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		runGenerate(os.Args[2:])
+		return
 	}
-	return
-}
 
-func main() {
 	debug := flag.Bool("d", false, "enable logging trace")
-	plan9 := flag.Bool("9", false, "Plan 9 Sudoku puzzle format")
+	plan9 := flag.Bool("9", false, "Plan 9 Sudoku puzzle format (shorthand for -format=col)")
 	pprof := flag.Bool("p", false, "enable pprof")
+	explain := flag.Bool("explain", false, "print the human-style deductions used while solving")
+	informat := flag.String("format", fmtAuto, "puzzle input format: auto|row|col|line|sdk|json")
+	outformat := flag.String("output", "", "emit the solved board in this format instead of the usual summary: row|col|line|sdk|json|grid")
+	checkOnly := flag.Bool("check-only", false, "validate the puzzle and exit, without solving it")
 
 	flag.Parse()
 
@@ -167,6 +153,11 @@ func main() {
 		defer profile.Start().Stop()
 	}
 
+	format := *informat
+	if *plan9 {
+		format = fmtCol
+	}
+
 	files := flag.Args()
 	for _, file := range files {
 		sudoku, err := ioutil.ReadFile(file)
@@ -174,18 +165,35 @@ func main() {
 			log.Fatal(err)
 		}
 
-		puzzle, err := parseboard(string(sudoku), *plan9)
-		if err != nil {
+		var puzzle board
+		if err := puzzle.Unmarshal(sudoku, format); err != nil {
 			log.Fatal(err)
 		}
 
+		if err := Validate(puzzle); err != nil {
+			fmt.Printf("%s: invalid puzzle: %v\n", file, err)
+			continue
+		}
+		if *checkOnly {
+			fmt.Printf("%s: ok\n", file)
+			continue
+		}
+
 		fmt.Println("Puzzle: ", file)
 
-		solution, impossible := solve(puzzle)
+		solution, impossible := solve(puzzle, *explain)
 		if impossible {
 			fmt.Printf("%s: solution isn't possible\n", file)
-		} else {
-			fmt.Printf("%s: is solved? %t\n", file, unknownCount(solution) == 0)
+			continue
+		}
+
+		fmt.Printf("%s: is solved? %t\n", file, unknownCount(solution) == 0)
+		if *outformat != "" {
+			out, err := solution.Marshal(*outformat)
+			if err != nil {
+				log.Fatal(err)
+			}
+			os.Stdout.Write(out)
 		}
 	}
 }
@@ -210,16 +218,20 @@ func main() {
 // col, row or box cells, note the change and start over.
 //
 // if a change has not occured in this loop, can't solve the puzzle.
-// with this strategy, and try brute force (step 2)
+// with this strategy, and move to step 2.
 //
-// step 2: brute force substitution
-// for each cell that has only two values, push a copy of the puzzle
-// on stack and, assign one of the two values and try to solve it using
-// elimination (step 1).  that doesn't succeed, pop the stack, try the second
-// value by assinging it, pushing that copy of the puzzle on the stack and
-// trying to solve it. substituted values that aren't correct will result
-// in impossible values for cells and will be abandoned.
-func solve(puzzle board) (board, bool) {
+// step 2: human-style rules
+// with no more eliminations to make, apply the rule engine in rules.go
+// (naked/hidden subsets, pointing pairs, X-Wing, Swordfish); each rule
+// that fires is followed by another elimination pass, and the whole
+// thing repeats until nothing more can be deduced this way.
+//
+// step 3: exact-cover substitution
+// whatever remains unknown is solved with Knuth's Dancing Links
+// (Algorithm X), see dlx.go: the puzzle is reformulated as an exact
+// cover problem and searched directly, rather than by guessing values
+// and re-running elimination on each branch.
+func solve(puzzle board, explain bool) (board, bool) {
 	printPuzzle(puzzle)
 	unknowns := unknownCount(puzzle)
 	// step 1: elimination
@@ -231,40 +243,53 @@ func solve(puzzle board) (board, bool) {
 	fmt.Println("After step1: ", unknowns-unknownCount(puzzle), "/", unknowns)
 	printPuzzle(puzzle)
 
-	// step2: substitution
-	// try all 2,3,4...-possibility cells, retracting when it doesn't work.
-	// use recursion to try the branches; could use a stack implementation
-	// but recursion is easier to sort out.  elimination and substitution
-	// are used
+	// step2: human-style rules
+	var deductions []Elimination
 	if unknownCount(puzzle) != 0 {
-		puzzle, impossible = substitution(puzzle, 2)
+		puzzle, deductions = humansolve(puzzle)
+	}
+	if explain {
+		printExplanation(deductions)
 	}
 
 	fmt.Println("After step2: ", unknowns-unknownCount(puzzle), "/", unknowns)
+	printPuzzle(puzzle)
+
+	// step3: substitution
+	// whatever elimination and the rule engine couldn't pin down is
+	// handed to the exact-cover solver in dlx.go.
+	if unknownCount(puzzle) != 0 {
+		puzzle, impossible = substitution(puzzle)
+	}
+
+	fmt.Println("After step3: ", unknowns-unknownCount(puzzle), "/", unknowns)
 	fmt.Println("Solution:")
 	printPuzzle(puzzle)
 
 	return puzzle, impossible
 }
 
-// eliminate all hints to discover cell values
-// and use those as further hints.
+// eliminate all hints to discover cell values and use those as further
+// hints. The actual work happens on a BitBoard (see bitboard.go), which
+// avoids the tuple allocations the old []*cell-based version made on
+// every single cell check; puzzle/pz stay the public board type so the
+// rest of the package is unaffected.
 func elimination(puzzle board) (pz board, found, impossible bool) {
-	pz = replicate(puzzle)
+	bb := boardToBitBoard(puzzle)
 
 	changed := true
 	for changed {
-		changed, impossible = findOne(pz)
+		changed, impossible = findOneBB(&bb)
 		if impossible {
 			log.Println("solution not possible")
-			return
+			return bb.toBoard(), found, true
 		}
 		if changed {
 			found = true
 		}
 	}
 
-	return
+	return bb.toBoard(), found, false
 }
 
 func printPuzzle(puzzle board) {
@@ -279,303 +304,12 @@ func printPuzzle(puzzle board) {
 	}
 }
 
-// find possible values for elem by looking at existing
-// values in tuple and eliminating them from possibles for
-// this element.
-func findpossibles(set tuple, elem int) int {
-	for i, v := range set {
-		if i != elem && v.value() != 0 {
-			set[elem].clearslot(uint(v.value()))
-		}
-	}
-	// openslots := set[elem].possible()
-	return set[elem].pcount()
-}
-
-// check to see if cells in tuple other than elem have
-// the ability to accept value (if value is possible)
-func hasval(set tuple, elem int, value int) bool {
-	for i, v := range set {
-		if i != elem {
-			if v.value() == value || v.slotisset(uint(value)) {
-				return true
-			}
-		}
-	}
-	return false
-}
-
-// find any slots that are unique to elem's cell; if
-// checkzero is set, eliminate possible values of other
-// unknown cells in tuple.
-func uniqueslot(set tuple, elem int, checkzeros bool) int {
-	pos := set[elem].possible()
-	val := pos
-	for i, v := range set {
-		if i != elem && (checkzeros || v.value() != 0) {
-			val ^= v.possible()
-			val &= pos
-		}
-		if val == 0 {
-			return 0
-		}
-	}
-	return pos
-}
-
-func getrowtuple(puzzle board, i int) tuple {
-	t := puzzle[i][:]
-	// printTuple("getrowtuple", t)
-	return t
-}
-
-func getcoltuple(puzzle board, j int) tuple {
-	t := make([]*cell, 9)
-	for i := 0; i < 9; i++ {
-		t[i] = puzzle[i][j]
-	}
-	// printTuple("getcoltuple", t)
-	return t
-}
-
-// make a tuple from the 3x3 box that cell(i,j) is in; put cell(i,j) in the
-// first slot of the tuple
-func getboxtuple(puzzle board, i, j int) tuple {
-	t := make([]*cell, 9)
-	x := i / 3
-	y := j / 3
-	for k := 0; k < 3; k++ {
-		for l := 0; l < 3; l++ {
-			xn := x*3 + k
-			yn := y*3 + l
-			t[k*3+l] = puzzle[xn][yn]
-			// if we're at cell[i][j]
-			if xn == i && yn == j && (k*3+l) != 0 {
-				// swap t[0] with this cell
-				t[0], t[k*3+l] = t[k*3+l], t[0]
-			}
-		}
-	}
-	// printTuple("getboxtuple", t)
-	return t
-}
-
-// find matching cell that has the same exact two possible
-// bits turned on. the cell at elem must have exactly 2
-// possible values
-func findmatching(set tuple, elem int) (bool, int) {
-	openslots := set[elem].possible()
-
-	// should be an error, if it ever happens.
-	if bitcount(openslots) != 2 {
-		return false, -1
-	}
-
-	for i, v := range set {
-		poss := v.possible()
-		if i != elem && v.value() == 0 && poss == openslots {
-			return true, i
-		}
-	}
-	return false, -1
-}
-
-// removeslots removes the slots in elements ea and eb from
-// other slots in the tuple
-func removeslots(set tuple, ea, eb int) bool {
-	openslots := set[ea].possible()
-	// assert openslots == set[eb].possible() && bitcount(openslots) == 2
-	spokenfor := bitvalues(openslots)
-
-	found := false
-	count := 0
-	for i, v := range set {
-		// unset values other than ea, eb
-		if i != ea && i != eb && v.value() == 0 {
-			for _, s := range spokenfor {
-				if v.slotisset(uint(s)) {
-					v.clearslot(uint(s))
-				}
-			}
-			if v.pcount() == 1 {
-				v.setvalue(bitvalue(v.possible()))
-				found = true
-				count++
-			}
-		}
-	}
-
-	// log.Println("removeslots: ", found, count)
-	return found
-}
-
-// for each cell, eliminate values that are already its row, col and box.
-// if there is only one possible value, assign it and return true. if there
-// are zero possibles, then return impossible.
-func checkCell(puzzle board, i, j int) (changed, impossible bool) {
-	openslots := puzzle[i][j].possible()
-	// s := strconv.FormatInt(int64(openslots), 2)
-	// log.Printf("row/col/box check cell(%d, %d), value %d, possibles %s\n", i, j, puzzle[i][j].value(), s)
-
-	possibles := bitcount(openslots)
-	if possibles < 2 {
-		return
-	}
-
-	tr := getrowtuple(puzzle, i)
-	tc := getcoltuple(puzzle, j)
-	tb := getboxtuple(puzzle, i, j)
-
-	possibles = findpossibles(tb, 0)
-	if possibles > 1 {
-		possibles = findpossibles(tc, i)
-	}
-	if possibles > 1 {
-		possibles = findpossibles(tr, j)
-	}
-
-	openslots = puzzle[i][j].possible()
-	// s = strconv.FormatInt(int64(openslots), 2)
-	// log.Printf("after row,col,box check cell(%d,%d) possibles: %s\n", i, j, s)
-
-	switch possibles {
-	case 0: // impossible
-		// log.Println("case 0: solution impossible")
-		impossible = true
-		return
-
-	case 1: // single value, assign it, turn off all possibles
-		val := bitvalue(openslots)
-		puzzle[i][j].setvalue(val)
-		// log.Printf("case 1: changed cell(%d,%d) to %d\n", i, j, puzzle[i][j].value())
-		changed = true
-		return
-
-	case 2: // exactly two possible values
-		// log.Println("case 2: search")
-		ok, jj := findmatching(tr, j)
-		if ok {
-			// remove the 2 matching values in j and jj from other slots
-			if removeslots(tr, j, jj) {
-				changed = true
-				return
-			}
-		}
-		ok, ii := findmatching(tc, i)
-		if ok {
-			// remove the two matching values in i, ii from other slots
-			if removeslots(tc, i, ii) {
-				changed = true
-				return
-			}
-		}
-		ok, bb := findmatching(tb, 0)
-		if ok {
-			// remove the two matching values in 0 and bb from other slots
-			if removeslots(tb, 0, bb) {
-				changed = true
-				return
-			}
-		}
-
-	default:
-		// for each possible number, check row, col, box tuples
-		// to see if the other cells can also have that value
-		// if none can have that value, then this cell must be
-		// that value:
-
-		pb := puzzle[i][j].possible()
-		for n := 1; pb != 0; n++ {
-			if pb&1 == 1 && !hasval(tr, j, n) && !hasval(tc, i, n) && !hasval(tb, 0, n) {
-				puzzle[i][j].setvalue(n)
-				// log.Printf("default: #1: changed cell(%d,%d) to %d\n", i, j, puzzle[i][j].value())
-				changed = true
-				return
-			}
-			pb >>= 1
-		}
-
-		for _, checkzeros := range []bool{false, true} {
-			ur := uniqueslot(tr, j, checkzeros)
-			uc := uniqueslot(tc, i, checkzeros)
-			ub := uniqueslot(tb, 0, checkzeros)
-			bit := ur & uc & ub
-			if bitcount(bit) == 1 {
-				puzzle[i][j].setvalue(bitvalue(bit))
-				// log.Printf("default: #2: changed cell(%d,%d) to %d\n", i, j, puzzle[i][j].value())
-				changed = true
-				return
-			}
-		}
-	}
-
-	return
-}
-
-// for each cell, find one that doesn't have a value and look through
-// all row, column and box cells; elimintate all hints and previously
-// filled values. if at least one cell value changed, return
-func findOne(puzzle board) (changed, impossible bool) {
-	for i := 0; i < 9; i++ {
-		for j := 0; j < 9; j++ {
-			changed, impossible = checkCell(puzzle, i, j)
-			if changed || impossible {
-				return
-			}
-		}
-	}
-
-	// no cells with a single possible value
-	// look through cells in each row, each column and each box
-	// looking for possibles that are only two
-	return false, false
-}
-
-// substitution: for every cell that has npossibles, try to
-// solve the puzzle by trying each of the possible values and
-// restart elimination and if needed more substitution. try
-// higher values of npossibles up to the maximum 9, if the
-// puzzle is unsolved.
-func substitution(puzzle board, npossibles int) (pz board, impossible bool) {
-	log.Printf("substitution of %d possibles", npossibles)
-	if npossibles > 9 {
-		return pz, true
-	}
-
-	pz = replicate(puzzle)
-	changed := false
-
-	for i := 0; i < 9; i++ {
-		for j := 0; j < 9; j++ {
-			np := bitcount(pz[i][j].possible())
-			if np == npossibles {
-				possibles := bitvalues(pz[i][j].possible())
-
-				for _, p := range possibles {
-					log.Printf("trying %d for cell(%d, %d)\n", p, i, j)
-					pz[i][j].setvalue(p)
-					pz, changed, impossible = elimination(pz)
-					if impossible {
-						pz = replicate(puzzle)
-						continue
-					}
-					if changed {
-						pz, impossible = substitution(pz, npossibles)
-						if impossible {
-							pz = replicate(puzzle)
-						}
-					}
-				}
-			}
-		}
-	}
-
-	if unknownCount(pz) != 0 {
-		npossibles++
-		pz, impossible = substitution(pz, npossibles)
-	}
-
-	return pz, impossible
+// substitution finds the remaining unknown cells with Knuth's Dancing
+// Links (Algorithm X) instead of guessing-and-backtracking by hand: the
+// puzzle is reformulated as an exact cover problem (see dlx.go) and
+// handed to dlxSolve, which both searches and applies the solution.
+func substitution(puzzle board) (pz board, impossible bool) {
+	return dlxSolve(puzzle)
 }
 
 func unknownCount(puzzle board) (unknowns int) {
@@ -631,13 +365,3 @@ func bitvalues(bv int) []int {
 	}
 	return list
 }
-
-func printTuple(n string, t tuple) {
-	fmt.Print(n, "[ ")
-	for _, v := range t {
-		fmt.Print(v.value())
-		s := strconv.FormatInt(int64(v.possible()), 2)
-		fmt.Printf("(%09s) ", s)
-	}
-	fmt.Println("]")
-}
@@ -0,0 +1,461 @@
+package main
+
+import "fmt"
+
+// Elimination records a single deduction made by the human-style rule
+// engine: candidate Value was removed from cell (Row, Col), with Reason
+// explaining why and Rule naming the technique that found it (used by
+// the difficulty rating in generate.go).
+type Elimination struct {
+	Row, Col, Value int
+	Reason          string
+	Rule            string
+}
+
+// pos is a board coordinate; unit is the 9 coordinates that make up a
+// row, column or box.
+type pos struct{ r, c int }
+type unit [9]pos
+
+// allUnits returns the 27 units of a puzzle: rows 0-8, columns 9-17 and
+// boxes 18-26.
+func allUnits() [27]unit {
+	var units [27]unit
+	for i := 0; i < 9; i++ {
+		for j := 0; j < 9; j++ {
+			units[i][j] = pos{i, j}
+			units[9+i][j] = pos{j, i}
+		}
+	}
+	for b := 0; b < 9; b++ {
+		br, bc := (b/3)*3, (b%3)*3
+		k := 0
+		for dr := 0; dr < 3; dr++ {
+			for dc := 0; dc < 3; dc++ {
+				units[18+b][k] = pos{br + dr, bc + dc}
+				k++
+			}
+		}
+	}
+	return units
+}
+
+func cellsOf(puzzle board, u unit) [9]*cell {
+	var cs [9]*cell
+	for i, p := range u {
+		cs[i] = puzzle[p.r][p.c]
+	}
+	return cs
+}
+
+// combinations returns every way to choose size elements of idx (the
+// elements themselves, not their positions in idx) without regard to
+// order.
+func combinations(idx []int, size int) [][]int {
+	var result [][]int
+	if size <= 0 || size > len(idx) {
+		return result
+	}
+	var rec func(start int, chosen []int)
+	rec = func(start int, chosen []int) {
+		if len(chosen) == size {
+			cp := make([]int, size)
+			copy(cp, chosen)
+			result = append(result, cp)
+			return
+		}
+		for i := start; i < len(idx); i++ {
+			rec(i+1, append(chosen, idx[i]))
+		}
+	}
+	rec(0, nil)
+	return result
+}
+
+func subsetName(size int) string {
+	switch size {
+	case 2:
+		return "pair"
+	case 3:
+		return "triple"
+	case 4:
+		return "quad"
+	}
+	return fmt.Sprintf("%d-subset", size)
+}
+
+func cellname(p pos) string {
+	return fmt.Sprintf("(r%d,c%d)", p.r+1, p.c+1)
+}
+
+func cellnames(u unit, idx []int) string {
+	s := ""
+	for i, n := range idx {
+		if i > 0 {
+			s += " and "
+		}
+		s += cellname(u[n])
+	}
+	return s
+}
+
+func valueset(values []int) string {
+	s := "{"
+	for i, v := range values {
+		if i > 0 {
+			s += ","
+		}
+		s += fmt.Sprintf("%d", v)
+	}
+	return s + "}"
+}
+
+// nakedSubset finds `size` unknown cells in a unit whose combined
+// candidates are exactly `size` values, and removes those values from
+// every other cell in the unit (naked pairs/triples/quads).
+func nakedSubset(puzzle board, size int) ([]Elimination, bool) {
+	for _, u := range allUnits() {
+		cs := cellsOf(puzzle, u)
+
+		var idx []int
+		for i, c := range cs {
+			if n := c.pcount(); c.value() == 0 && n > 0 && n <= size {
+				idx = append(idx, i)
+			}
+		}
+
+		for _, combo := range combinations(idx, size) {
+			mask := 0
+			for _, i := range combo {
+				mask |= cs[i].possible()
+			}
+			if bitcount(mask) != size {
+				continue
+			}
+
+			inSet := map[int]bool{}
+			for _, i := range combo {
+				inSet[i] = true
+			}
+
+			var changes []Elimination
+			for i, c := range cs {
+				if inSet[i] || c.value() != 0 {
+					continue
+				}
+				for _, v := range bitvalues(mask) {
+					if c.slotisset(uint(v)) {
+						changes = append(changes, Elimination{
+							Row: u[i].r, Col: u[i].c, Value: v,
+							Rule: "naked-" + subsetName(size),
+							Reason: fmt.Sprintf("cells %s form a naked %s on %s, removing %d from %s",
+								cellnames(u, combo), subsetName(size), valueset(bitvalues(mask)), v, cellname(u[i])),
+						})
+					}
+				}
+			}
+			if len(changes) > 0 {
+				return changes, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// hiddenSubset finds `size` values that, within a unit, can only go in
+// the same `size` cells, and removes every other candidate from those
+// cells (hidden pairs/triples).
+func hiddenSubset(puzzle board, size int) ([]Elimination, bool) {
+	for _, u := range allUnits() {
+		cs := cellsOf(puzzle, u)
+
+		posFor := map[int][]int{}
+		for v := 1; v <= 9; v++ {
+			for i, c := range cs {
+				if c.value() == 0 && c.slotisset(uint(v)) {
+					posFor[v] = append(posFor[v], i)
+				}
+			}
+		}
+
+		var values []int
+		for v := 1; v <= 9; v++ {
+			if n := len(posFor[v]); n > 0 && n <= size {
+				values = append(values, v)
+			}
+		}
+
+		for _, comboValues := range combinations(values, size) {
+			cellSet := map[int]bool{}
+			for _, v := range comboValues {
+				for _, i := range posFor[v] {
+					cellSet[i] = true
+				}
+			}
+			if len(cellSet) != size {
+				continue
+			}
+
+			var idx []int
+			for i := range cellSet {
+				idx = append(idx, i)
+			}
+
+			var changes []Elimination
+			for i := range cellSet {
+				c := cs[i]
+				for v := 1; v <= 9; v++ {
+					if !contains(comboValues, v) && c.slotisset(uint(v)) {
+						changes = append(changes, Elimination{
+							Row: u[i].r, Col: u[i].c, Value: v,
+							Rule: "hidden-" + subsetName(size),
+							Reason: fmt.Sprintf("%s are a hidden %s on %s, removing %d from %s",
+								cellnames(u, idx), subsetName(size), valueset(comboValues), v, cellname(u[i])),
+						})
+					}
+				}
+			}
+			if len(changes) > 0 {
+				return changes, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func contains(s []int, v int) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// pointingPairs looks, within each box, for a value confined to a single
+// row or column; that value can then be removed from the rest of that
+// row/column outside the box (box/line reduction).
+func pointingPairs(puzzle board) ([]Elimination, bool) {
+	units := allUnits()
+	for b := 18; b < 27; b++ {
+		u := units[b]
+		cs := cellsOf(puzzle, u)
+
+		for v := 1; v <= 9; v++ {
+			var rows, cols map[int]bool = map[int]bool{}, map[int]bool{}
+			var idx []int
+			for i, c := range cs {
+				if c.value() == 0 && c.slotisset(uint(v)) {
+					rows[u[i].r] = true
+					cols[u[i].c] = true
+					idx = append(idx, i)
+				}
+			}
+			if len(idx) == 0 {
+				continue
+			}
+
+			var changes []Elimination
+			if len(rows) == 1 {
+				var r int
+				for k := range rows {
+					r = k
+				}
+				line := units[r]
+				lcs := cellsOf(puzzle, line)
+				for i, c := range lcs {
+					if line[i].c/3 == u[0].c/3 && line[i].r/3 == u[0].r/3 {
+						continue
+					}
+					if c.value() == 0 && c.slotisset(uint(v)) {
+						changes = append(changes, Elimination{
+							Row: line[i].r, Col: line[i].c, Value: v,
+							Rule: "pointing-pair",
+							Reason: fmt.Sprintf("%d is confined to %s in box, removing %d from %s",
+								v, cellnames(u, idx), v, cellname(line[i])),
+						})
+					}
+				}
+			} else if len(cols) == 1 {
+				var c0 int
+				for k := range cols {
+					c0 = k
+				}
+				line := units[9+c0]
+				lcs := cellsOf(puzzle, line)
+				for i, c := range lcs {
+					if line[i].c/3 == u[0].c/3 && line[i].r/3 == u[0].r/3 {
+						continue
+					}
+					if c.value() == 0 && c.slotisset(uint(v)) {
+						changes = append(changes, Elimination{
+							Row: line[i].r, Col: line[i].c, Value: v,
+							Rule: "pointing-pair",
+							Reason: fmt.Sprintf("%d is confined to %s in box, removing %d from %s",
+								v, cellnames(u, idx), v, cellname(line[i])),
+						})
+					}
+				}
+			}
+			if len(changes) > 0 {
+				return changes, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// fish implements the X-Wing (size 2) and Swordfish (size 3) rules: if a
+// value's candidates in `size` rows fall inside exactly `size` columns
+// (or vice versa), that value can be removed from those columns (rows)
+// in every other row (column).
+func fish(puzzle board, size int) ([]Elimination, bool) {
+	if changes, ok := fishDirection(puzzle, size, false); ok {
+		return changes, ok
+	}
+	return fishDirection(puzzle, size, true)
+}
+
+func fishDirection(puzzle board, size int, transpose bool) ([]Elimination, bool) {
+	units := allUnits()
+	base := units[0:9]
+	cover := units[9:18]
+	if transpose {
+		base, cover = units[9:18], units[0:9]
+	}
+
+	for v := 1; v <= 9; v++ {
+		var lines []int
+		coverIdx := map[int][]int{} // line index -> cover-unit indexes holding v
+		for li, u := range base {
+			cs := cellsOf(puzzle, u)
+			var idx []int
+			for i, c := range cs {
+				if c.value() == 0 && c.slotisset(uint(v)) {
+					if transpose {
+						idx = append(idx, u[i].r)
+					} else {
+						idx = append(idx, u[i].c)
+					}
+				}
+			}
+			if n := len(idx); n >= 2 && n <= size {
+				lines = append(lines, li)
+				coverIdx[li] = idx
+			}
+		}
+
+		for _, combo := range combinations(lines, size) {
+			coverSet := map[int]bool{}
+			for _, li := range combo {
+				for _, ci := range coverIdx[li] {
+					coverSet[ci] = true
+				}
+			}
+			if len(coverSet) != size {
+				continue
+			}
+
+			baseSet := map[int]bool{}
+			for _, li := range combo {
+				baseSet[li] = true
+			}
+
+			var changes []Elimination
+			for ci := range coverSet {
+				u := cover[ci]
+				cs := cellsOf(puzzle, u)
+				for i, c := range cs {
+					var line int
+					if transpose {
+						line = u[i].c
+					} else {
+						line = u[i].r
+					}
+					if baseSet[line] {
+						continue
+					}
+					if c.value() == 0 && c.slotisset(uint(v)) {
+						changes = append(changes, Elimination{
+							Row: u[i].r, Col: u[i].c, Value: v,
+							Rule: fishRule(size),
+							Reason: fmt.Sprintf("%s forms on %d across %d lines, removing %d from %s",
+								fishName(size), v, size, v, cellname(u[i])),
+						})
+					}
+				}
+			}
+			if len(changes) > 0 {
+				return changes, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func fishName(size int) string {
+	switch size {
+	case 2:
+		return "an X-Wing"
+	case 3:
+		return "a Swordfish"
+	}
+	return fmt.Sprintf("a %d-fish", size)
+}
+
+func fishRule(size int) string {
+	switch size {
+	case 2:
+		return "x-wing"
+	case 3:
+		return "swordfish"
+	}
+	return fmt.Sprintf("%d-fish", size)
+}
+
+// humanRules is the ordered pipeline applied between elimination and
+// substitution; the first rule that fires wins and the pipeline starts
+// over, mirroring the order a person would try these techniques.
+var humanRules = []func(board) ([]Elimination, bool){
+	func(p board) ([]Elimination, bool) { return nakedSubset(p, 2) },
+	func(p board) ([]Elimination, bool) { return nakedSubset(p, 3) },
+	func(p board) ([]Elimination, bool) { return nakedSubset(p, 4) },
+	func(p board) ([]Elimination, bool) { return hiddenSubset(p, 2) },
+	func(p board) ([]Elimination, bool) { return hiddenSubset(p, 3) },
+	pointingPairs,
+	func(p board) ([]Elimination, bool) { return fish(p, 2) },
+	func(p board) ([]Elimination, bool) { return fish(p, 3) },
+}
+
+// humansolve applies humanRules, and the cheap elimination pass after
+// every successful rule, until no rule fires. It returns every
+// deduction made so callers can print it with -explain.
+func humansolve(puzzle board) (board, []Elimination) {
+	var log []Elimination
+	for {
+		applied := false
+		for _, rule := range humanRules {
+			changes, ok := rule(puzzle)
+			if !ok {
+				continue
+			}
+			for _, e := range changes {
+				puzzle[e.Row][e.Col].clearslot(uint(e.Value))
+			}
+			log = append(log, changes...)
+			applied = true
+			break
+		}
+		if !applied {
+			break
+		}
+		puzzle, _, _ = elimination(puzzle)
+	}
+	return puzzle, log
+}
+
+func printExplanation(log []Elimination) {
+	for _, e := range log {
+		fmt.Println(e.Reason)
+	}
+}
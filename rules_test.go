@@ -0,0 +1,141 @@
+package main
+
+import "testing"
+
+// setCandidates resets c to unknown with exactly the given candidates open.
+func setCandidates(c *cell, values ...int) {
+	c.setvalue(0)
+	open := map[int]bool{}
+	for _, v := range values {
+		open[v] = true
+	}
+	for v := 1; v <= 9; v++ {
+		if !open[v] {
+			c.clearslot(uint(v))
+		}
+	}
+}
+
+func hasElimination(changes []Elimination, row, col, value int) bool {
+	for _, e := range changes {
+		if e.Row == row && e.Col == col && e.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// TestNakedSubsetPair plants a naked pair {1,2} at (r0,c0)/(r0,c1); a
+// third cell in the row can hold {1,2,3} and should lose 1 and 2.
+func TestNakedSubsetPair(t *testing.T) {
+	puzzle := newBlankBoard()
+	setCandidates(puzzle[0][0], 1, 2)
+	setCandidates(puzzle[0][1], 1, 2)
+	setCandidates(puzzle[0][2], 1, 2, 3)
+	for c := 3; c < 9; c++ {
+		puzzle[0][c].clearslot(1)
+		puzzle[0][c].clearslot(2)
+	}
+
+	changes, ok := nakedSubset(puzzle, 2)
+	if !ok {
+		t.Fatal("nakedSubset(puzzle, 2) = false, want true")
+	}
+	if len(changes) != 2 {
+		t.Fatalf("nakedSubset changes = %+v, want exactly 2 eliminations at (r0,c2)", changes)
+	}
+	for _, v := range []int{1, 2} {
+		if !hasElimination(changes, 0, 2, v) {
+			t.Errorf("missing elimination of %d at (r0,c2): %+v", v, changes)
+		}
+		if changes[0].Rule != "naked-pair" {
+			t.Errorf("Rule = %q, want %q", changes[0].Rule, "naked-pair")
+		}
+	}
+}
+
+// TestHiddenSubsetPair plants a hidden pair: values 4 and 5 are only
+// possible at (r0,c0) and (r0,c1) in the row, which also carry an extra
+// stray candidate each that the hidden pair should strip.
+func TestHiddenSubsetPair(t *testing.T) {
+	puzzle := newBlankBoard()
+	setCandidates(puzzle[0][0], 4, 5, 6)
+	setCandidates(puzzle[0][1], 4, 5, 7)
+	for c := 2; c < 9; c++ {
+		setCandidates(puzzle[0][c], 1, 2, 3, 6, 7, 8, 9)
+	}
+
+	changes, ok := hiddenSubset(puzzle, 2)
+	if !ok {
+		t.Fatal("hiddenSubset(puzzle, 2) = false, want true")
+	}
+	if len(changes) != 2 {
+		t.Fatalf("hiddenSubset changes = %+v, want exactly 2 eliminations", changes)
+	}
+	if !hasElimination(changes, 0, 0, 6) {
+		t.Errorf("missing elimination of stray candidate 6 at (r0,c0): %+v", changes)
+	}
+	if !hasElimination(changes, 0, 1, 7) {
+		t.Errorf("missing elimination of stray candidate 7 at (r0,c1): %+v", changes)
+	}
+	if changes[0].Rule != "hidden-pair" {
+		t.Errorf("Rule = %q, want %q", changes[0].Rule, "hidden-pair")
+	}
+}
+
+// TestPointingPairs confines candidate 7 within box 0 to row 0 (cells
+// (r0,c0) and (r0,c1)); it should then be removed from the rest of row
+// 0 outside the box.
+func TestPointingPairs(t *testing.T) {
+	puzzle := newBlankBoard()
+	for _, p := range []pos{{0, 2}, {1, 0}, {1, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}} {
+		puzzle[p.r][p.c].clearslot(7)
+	}
+
+	changes, ok := pointingPairs(puzzle)
+	if !ok {
+		t.Fatal("pointingPairs(puzzle) = false, want true")
+	}
+	for c := 3; c < 9; c++ {
+		if !hasElimination(changes, 0, c, 7) {
+			t.Errorf("missing elimination of 7 at (r0,c%d): %+v", c, changes)
+		}
+	}
+	if changes[0].Rule != "pointing-pair" {
+		t.Errorf("Rule = %q, want %q", changes[0].Rule, "pointing-pair")
+	}
+}
+
+// TestFishXWing confines candidate 9 in rows 0 and 3 to the same two
+// columns (0 and 4); the X-Wing rule should then remove 9 from those
+// columns in every other row.
+func TestFishXWing(t *testing.T) {
+	puzzle := newBlankBoard()
+	for _, r := range []int{0, 3} {
+		for c := 0; c < 9; c++ {
+			if c != 0 && c != 4 {
+				puzzle[r][c].clearslot(9)
+			}
+		}
+	}
+
+	changes, ok := fish(puzzle, 2)
+	if !ok {
+		t.Fatal("fish(puzzle, 2) = false, want true")
+	}
+	for _, r := range []int{1, 2, 4, 5, 6, 7, 8} {
+		for _, c := range []int{0, 4} {
+			if !hasElimination(changes, r, c, 9) {
+				t.Errorf("missing elimination of 9 at (r%d,c%d): %+v", r, c, changes)
+			}
+		}
+	}
+	for _, e := range changes {
+		if e.Row == 0 || e.Row == 3 {
+			t.Errorf("X-Wing eliminated from its own base row: %+v", e)
+		}
+		if e.Rule != "x-wing" {
+			t.Errorf("Rule = %q, want %q", e.Rule, "x-wing")
+		}
+	}
+}
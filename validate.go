@@ -0,0 +1,76 @@
+package main
+
+import "fmt"
+
+// Validate reports the first problem found in puzzle: a duplicate given
+// digit in some row, column or box, or an empty cell that has no
+// candidate left once the givens are taken into account. A nil result
+// doesn't guarantee the puzzle is solvable, only that it isn't
+// trivially broken.
+func Validate(puzzle board) error {
+	units := allUnits()
+	for i, u := range units {
+		cs := cellsOf(puzzle, u)
+
+		seen := map[int]int{} // value -> first index it was seen at
+		for idx, c := range cs {
+			v := c.value()
+			if v == 0 {
+				continue
+			}
+			if first, ok := seen[v]; ok {
+				return duplicateError(i, u, first, idx, v)
+			}
+			seen[v] = idx
+		}
+	}
+
+	return feasible(puzzle)
+}
+
+func duplicateError(unitIdx int, u unit, i, j, v int) error {
+	switch {
+	case unitIdx < 9:
+		return fmt.Errorf("duplicate %d in row %d at columns %d and %d", v, u[i].r+1, u[i].c+1, u[j].c+1)
+	case unitIdx < 18:
+		return fmt.Errorf("duplicate %d in column %d at rows %d and %d", v, u[i].c+1, u[i].r+1, u[j].r+1)
+	default:
+		return fmt.Errorf("duplicate %d in box at %s and %s", v, cellname(u[i]), cellname(u[j]))
+	}
+}
+
+// feasible checks that every empty cell still has at least one
+// candidate value once its row, column and box givens are removed; it's
+// a cheap pass over the givens alone, not a full elimination run.
+func feasible(puzzle board) error {
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			if puzzle[r][c].value() != 0 {
+				continue
+			}
+
+			used := 0
+			for i := 0; i < 9; i++ {
+				if v := puzzle[r][i].value(); v != 0 {
+					used |= 1 << uint(v-1)
+				}
+				if v := puzzle[i][c].value(); v != 0 {
+					used |= 1 << uint(v-1)
+				}
+			}
+			br, bc := (r/3)*3, (c/3)*3
+			for i := 0; i < 3; i++ {
+				for j := 0; j < 3; j++ {
+					if v := puzzle[br+i][bc+j].value(); v != 0 {
+						used |= 1 << uint(v-1)
+					}
+				}
+			}
+
+			if used == 0x1ff {
+				return fmt.Errorf("cell %s has no remaining candidates", cellname(pos{r, c}))
+			}
+		}
+	}
+	return nil
+}
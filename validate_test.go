@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func lineBoard(t *testing.T, line string) board {
+	t.Helper()
+	var puzzle board
+	if err := puzzle.Unmarshal([]byte(line), fmtLine); err != nil {
+		t.Fatalf("Unmarshal(%q): %v", line, err)
+	}
+	return puzzle
+}
+
+func TestValidateOK(t *testing.T) {
+	if err := Validate(lineBoard(t, sampleLine)); err != nil {
+		t.Errorf("Validate(sampleLine) = %v, want nil", err)
+	}
+}
+
+func TestValidateDuplicateInRow(t *testing.T) {
+	line := "5..5....." + strings.Repeat(".........", 8)
+	err := Validate(lineBoard(t, line))
+	if err == nil {
+		t.Fatal("Validate = nil, want a duplicate-in-row error")
+	}
+	want := "duplicate 5 in row 1 at columns 1 and 4"
+	if err.Error() != want {
+		t.Errorf("Validate error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestValidateDuplicateInColumn(t *testing.T) {
+	line := "5........" + "........." + "........." + "5........" + strings.Repeat(".........", 5)
+	err := Validate(lineBoard(t, line))
+	if err == nil {
+		t.Fatal("Validate = nil, want a duplicate-in-column error")
+	}
+	want := "duplicate 5 in column 1 at rows 1 and 4"
+	if err.Error() != want {
+		t.Errorf("Validate error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestValidateDuplicateInBox(t *testing.T) {
+	line := "5........" + ".5......." + strings.Repeat(".........", 7)
+	err := Validate(lineBoard(t, line))
+	if err == nil {
+		t.Fatal("Validate = nil, want a duplicate-in-box error")
+	}
+	want := "duplicate 5 in box at (r1,c1) and (r2,c2)"
+	if err.Error() != want {
+		t.Errorf("Validate error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestValidateFeasibleDeadCell(t *testing.T) {
+	// row 1 gives {2..9} everywhere but (1,1); column 1 gives {1} at
+	// (2,1) -- together they leave (1,1) with no candidate left, even
+	// though nothing conflicts outright.
+	line := ".23456789" + "1........" + strings.Repeat(".........", 7)
+	err := Validate(lineBoard(t, line))
+	if err == nil {
+		t.Fatal("Validate = nil, want a no-candidates-left error")
+	}
+	want := "cell (r1,c1) has no remaining candidates"
+	if err.Error() != want {
+		t.Errorf("Validate error = %q, want %q", err.Error(), want)
+	}
+}